@@ -20,9 +20,7 @@ import (
 	"net/http"
 	"strings"
 
-	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
-	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"github.com/aws/karpenter-core/pkg/utils/functional"
 	"github.com/aws/karpenter/pkg/apis"
@@ -35,6 +33,8 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/patrickmn/go-cache"
 	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -43,6 +43,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/aws/karpenter/pkg/cloudprovider/amifamily"
+	"github.com/aws/karpenter/pkg/cloudprovider/events"
 	awscontext "github.com/aws/karpenter/pkg/context"
 
 	coreapis "github.com/aws/karpenter-core/pkg/apis"
@@ -67,14 +68,27 @@ type CloudProvider struct {
 	instanceProvider     *InstanceProvider
 	kubeClient           client.Client
 	amiProvider          *amifamily.AMIProvider
+	ec2api               ec2iface.EC2API
+	instanceCache        *cache.Cache
+	provisionerCache     *cache.Cache
+	eventsMonitor        *events.Monitor
+}
+
+// EventsMonitor returns the SQS-backed interruption monitor for this CloudProvider so it
+// can be registered with the controller-runtime manager as a Runnable (mgr.Add).
+func (c *CloudProvider) EventsMonitor() *events.Monitor {
+	return c.eventsMonitor
 }
 
 func New(ctx awscontext.Context) *CloudProvider {
 	instanceTypeProvider := NewInstanceTypeProvider(ctx.Session, ctx.EC2API, ctx.SubnetProvider, ctx.UnavailableOfferingsCache, ctx.PricingProvider)
-	return &CloudProvider{
+	cloudProvider := &CloudProvider{
 		kubeClient:           ctx.KubeClient,
 		instanceTypeProvider: instanceTypeProvider,
 		amiProvider:          ctx.AMIProvider,
+		ec2api:               ctx.EC2API,
+		instanceCache:        newInstanceCache(),
+		provisionerCache:     newProvisionerCache(),
 		instanceProvider: NewInstanceProvider(
 			ctx,
 			aws.StringValue(ctx.Session.Config.Region),
@@ -85,6 +99,8 @@ func New(ctx awscontext.Context) *CloudProvider {
 			ctx.LaunchTemplateProvider,
 		),
 	}
+	cloudProvider.eventsMonitor = events.NewMonitor(ctx.SQSAPI, cloudProvider, ctx.EventRecorder)
+	return cloudProvider
 }
 
 // Create a machine given the constraints.
@@ -106,10 +122,26 @@ func (c *CloudProvider) Create(ctx context.Context, machine *v1alpha5.Machine) (
 	if err != nil {
 		return nil, fmt.Errorf("creating instance, %w", err)
 	}
+	// We already know the provisioner here, so cache it rather than relying on the
+	// karpenter.sh/provisioner-name tag, which can take several seconds to propagate to
+	// DescribeInstances.
+	c.cacheProvisionerForInstance(aws.StringValue(instance.InstanceId), machine.Labels[v1alpha5.ProvisionerNameLabelKey])
 	instanceType, _ := lo.Find(instanceTypes, func(i *cloudprovider.InstanceType) bool {
 		return i.Name == aws.StringValue(instance.InstanceType)
 	})
-	return c.instanceToMachine(ctx, instance, instanceType), nil
+	created := c.instanceToMachine(ctx, instance, instanceType)
+	if created.Annotations == nil {
+		created.Annotations = map[string]string{}
+	}
+	// isUserDataDrifted recomputes this hash with the live Provisioner, so it must be
+	// stamped with that same Provisioner here, not nil, or any Provisioner that sets
+	// KubeletConfiguration will permanently mismatch and drift on every reconcile.
+	provisioner := &v1alpha5.Provisioner{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: machine.Labels[v1alpha5.ProvisionerNameLabelKey]}, provisioner); err != nil {
+		provisioner = nil
+	}
+	created.Annotations[UserDataHashAnnotationKey] = userDataHash(provisioner, nodeTemplate)
+	return created, nil
 }
 
 // Link adds a tag to the cloudprovider machine to tell the cloudprovider that it's now owned by a Machine
@@ -130,6 +162,7 @@ func (c *CloudProvider) List(ctx context.Context) ([]*v1alpha5.Machine, error) {
 	}
 	var machines []*v1alpha5.Machine
 	for _, instance := range instances {
+		c.instanceCache.SetDefault(aws.StringValue(instance.InstanceId), instance)
 		instanceType, err := c.resolveInstanceTypeFromInstance(ctx, instance)
 		if err != nil {
 			return nil, fmt.Errorf("resolving instance type, %w", err)
@@ -188,6 +221,8 @@ func (c *CloudProvider) Delete(ctx context.Context, machine *v1alpha5.Machine) e
 		return fmt.Errorf("getting instance ID, %w", err)
 	}
 	ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With("id", id))
+	c.invalidateProvisionerCache(id)
+	c.instanceCache.Delete(id)
 	return c.instanceProvider.Delete(ctx, id)
 }
 
@@ -204,11 +239,16 @@ func (c *CloudProvider) IsMachineDrifted(ctx context.Context, machine *v1alpha5.
 	if err != nil {
 		return false, client.IgnoreNotFound(fmt.Errorf("resolving node template, %w", err))
 	}
-	amiDrifted, err := c.isAMIDrifted(ctx, machine, provisioner, nodeTemplate)
+	reason, err := c.isMachineDrifted(ctx, machine, provisioner, nodeTemplate)
 	if err != nil {
 		return false, err
 	}
-	return amiDrifted, nil
+	if reason == "" {
+		clearMachineCondition(machine, DriftedConditionType)
+		return false, nil
+	}
+	setMachineCondition(machine, DriftedConditionType, v1.ConditionTrue, string(reason))
+	return true, nil
 }
 
 // Name returns the CloudProvider implementation name.
@@ -301,20 +341,6 @@ func (c *CloudProvider) resolveInstanceTypeFromInstance(ctx context.Context, ins
 	return instanceType, nil
 }
 
-func (c *CloudProvider) resolveProvisionerFromInstance(ctx context.Context, instance *ec2.Instance) (*v1alpha5.Provisioner, error) {
-	provisioner := &v1alpha5.Provisioner{}
-	tag, ok := lo.Find(instance.Tags, func(t *ec2.Tag) bool {
-		return aws.StringValue(t.Key) == v1alpha5.ProvisionerNameLabelKey
-	})
-	if !ok {
-		return nil, errors.NewNotFound(schema.GroupResource{Group: v1alpha5.Group, Resource: "Provisioner"}, "")
-	}
-	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: aws.StringValue(tag.Value)}, provisioner); err != nil {
-		return nil, err
-	}
-	return provisioner, nil
-}
-
 func (c *CloudProvider) instanceToMachine(ctx context.Context, instance *ec2.Instance, instanceType *cloudprovider.InstanceType) *v1alpha5.Machine {
 	machine := &v1alpha5.Machine{}
 	labels := map[string]string{}
@@ -343,6 +369,11 @@ func (c *CloudProvider) instanceToMachine(ctx context.Context, instance *ec2.Ins
 		strings.ToLower(aws.StringValue(instance.PrivateDnsName)),
 	)
 	machine.Labels = labels
+	if instance.State != nil {
+		state := instanceStateFromEC2(instance.State.Name)
+		status := lo.Ternary(state == InstanceStateRunning, v1.ConditionTrue, v1.ConditionFalse)
+		setMachineCondition(machine, InstanceStateConditionType, status, string(state))
+	}
 	machine.CreationTimestamp = metav1.Time{Time: aws.TimeValue(instance.LaunchTime)}
 	machine.Status.ProviderID = fmt.Sprintf("aws:///%s/%s", aws.StringValue(instance.Placement.AvailabilityZone), aws.StringValue(instance.InstanceId))
 	return machine
@@ -0,0 +1,68 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+)
+
+// DriftedConditionType is the Machine status condition that records the most recently
+// observed DriftReason, so the disruption controller can explain a drift-driven
+// replacement without recomputing drift itself.
+const DriftedConditionType apis.ConditionType = "Drifted"
+
+// InstanceStateConditionType is the Machine status condition that records the last
+// observed EC2 instance lifecycle state, so controllers that only watch Machines (rather
+// than calling EC2 directly) can react to an instance stopping or shutting down
+// out-of-band.
+const InstanceStateConditionType apis.ConditionType = "InstanceState"
+
+// setMachineCondition sets (or updates in place) the named condition on machine.Status.
+// Machine conditions are a Status field, not an annotation, so they're preserved by a
+// status-subresource-only update and are the correct place for data other controllers are
+// meant to observe.
+func setMachineCondition(machine *v1alpha5.Machine, conditionType apis.ConditionType, status v1.ConditionStatus, reason string) {
+	now := apis.VolatileTime{Inner: metav1.Now()}
+	for i := range machine.Status.Conditions {
+		if machine.Status.Conditions[i].Type == conditionType {
+			if machine.Status.Conditions[i].Status != status || machine.Status.Conditions[i].Reason != reason {
+				machine.Status.Conditions[i].Status = status
+				machine.Status.Conditions[i].Reason = reason
+				machine.Status.Conditions[i].LastTransitionTime = now
+			}
+			return
+		}
+	}
+	machine.Status.Conditions = append(machine.Status.Conditions, apis.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		LastTransitionTime: now,
+	})
+}
+
+// clearMachineCondition removes the named condition from machine.Status entirely, for
+// conditions (like Drifted) that should disappear rather than report False once they no
+// longer apply.
+func clearMachineCondition(machine *v1alpha5.Machine, conditionType apis.ConditionType) {
+	machine.Status.Conditions = lo.Filter(machine.Status.Conditions, func(c apis.Condition, _ int) bool {
+		return c.Type != conditionType
+	})
+}
@@ -0,0 +1,195 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/samber/lo"
+
+	"github.com/aws/karpenter/pkg/apis/v1alpha1"
+	"github.com/aws/karpenter/pkg/utils"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+)
+
+// DriftReason identifies the specific AWSNodeTemplate field that no longer matches the
+// state of the running instance. It is surfaced on the Machine so the disruption
+// controller can explain why a node is being replaced.
+type DriftReason string
+
+const (
+	AMIDrift             DriftReason = "AMIDrift"
+	SecurityGroupDrift   DriftReason = "SecurityGroupDrift"
+	SubnetDrift          DriftReason = "SubnetDrift"
+	UserDataDrift        DriftReason = "UserDataDrift"
+	InstanceProfileDrift DriftReason = "InstanceProfileDrift"
+	MetadataOptionsDrift DriftReason = "MetadataOptionsDrift"
+)
+
+// UserDataHashAnnotationKey stores the hash of the inputs used to render this Machine's
+// user-data at launch time, so later drift checks don't need to re-render it.
+const UserDataHashAnnotationKey = "karpenter.k8s.aws/user-data-hash"
+
+// isMachineDrifted runs every known drift check against the instance backing machine and
+// returns the first DriftReason it finds, or "" if the instance still matches nodeTemplate.
+func (c *CloudProvider) isMachineDrifted(ctx context.Context, machine *v1alpha5.Machine, provisioner *v1alpha5.Provisioner, nodeTemplate *v1alpha1.AWSNodeTemplate) (DriftReason, error) {
+	instanceID, err := utils.ParseInstanceID(machine.Status.ProviderID)
+	if err != nil {
+		return "", err
+	}
+	instance, err := c.instanceProvider.Get(ctx, instanceID)
+	if err != nil {
+		return "", fmt.Errorf("getting instance, %w", err)
+	}
+	amiDrifted, err := c.isAMIDrifted(ctx, machine, provisioner, nodeTemplate)
+	if err != nil {
+		return "", fmt.Errorf("checking ami drift, %w", err)
+	}
+	if amiDrifted {
+		return AMIDrift, nil
+	}
+	if drifted := isSecurityGroupDrifted(instance, nodeTemplate); drifted {
+		return SecurityGroupDrift, nil
+	}
+	if drifted := isSubnetDrifted(instance, nodeTemplate); drifted {
+		return SubnetDrift, nil
+	}
+	if drifted := isMetadataOptionsDrifted(instance, nodeTemplate); drifted {
+		return MetadataOptionsDrift, nil
+	}
+	drifted, err := c.isInstanceProfileDrifted(ctx, instance, nodeTemplate)
+	if err != nil {
+		return "", fmt.Errorf("checking instance profile drift, %w", err)
+	}
+	if drifted {
+		return InstanceProfileDrift, nil
+	}
+	if drifted := isUserDataDrifted(machine, provisioner, nodeTemplate); drifted {
+		return UserDataDrift, nil
+	}
+	return "", nil
+}
+
+// isSecurityGroupDrifted compares the resolved AWSNodeTemplate security groups against the
+// groups currently attached to all of the instance's network interfaces.
+func isSecurityGroupDrifted(instance *ec2.Instance, nodeTemplate *v1alpha1.AWSNodeTemplate) bool {
+	if len(nodeTemplate.Status.SecurityGroups) == 0 {
+		return false
+	}
+	expected := lo.Map(nodeTemplate.Status.SecurityGroups, func(sg v1alpha1.SecurityGroup, _ int) string { return sg.ID })
+	var actual []string
+	for _, ni := range instance.NetworkInterfaces {
+		for _, group := range ni.Groups {
+			actual = append(actual, aws.StringValue(group.GroupId))
+		}
+	}
+	return !setsEqual(expected, actual)
+}
+
+// isSubnetDrifted compares the resolved AWSNodeTemplate subnets against the subnet the
+// instance actually launched into.
+func isSubnetDrifted(instance *ec2.Instance, nodeTemplate *v1alpha1.AWSNodeTemplate) bool {
+	if len(nodeTemplate.Status.Subnets) == 0 {
+		return false
+	}
+	expected := lo.Map(nodeTemplate.Status.Subnets, func(subnet v1alpha1.Subnet, _ int) string { return subnet.ID })
+	return !lo.Contains(expected, aws.StringValue(instance.SubnetId))
+}
+
+// isMetadataOptionsDrifted compares the AWSNodeTemplate's desired IMDS configuration
+// against the configuration observed on the instance.
+func isMetadataOptionsDrifted(instance *ec2.Instance, nodeTemplate *v1alpha1.AWSNodeTemplate) bool {
+	if nodeTemplate.Spec.MetadataOptions == nil || instance.MetadataOptions == nil {
+		return false
+	}
+	desired := nodeTemplate.Spec.MetadataOptions
+	actual := instance.MetadataOptions
+	if desired.HTTPEndpoint != nil && aws.StringValue(desired.HTTPEndpoint) != aws.StringValue(actual.HttpEndpoint) {
+		return true
+	}
+	if desired.HTTPProtocolIPv6 != nil && aws.StringValue(desired.HTTPProtocolIPv6) != aws.StringValue(actual.HttpProtocolIpv6) {
+		return true
+	}
+	if desired.HTTPPutResponseHopLimit != nil && aws.Int64Value(desired.HTTPPutResponseHopLimit) != aws.Int64Value(actual.HttpPutResponseHopLimit) {
+		return true
+	}
+	if desired.HTTPTokens != nil && aws.StringValue(desired.HTTPTokens) != aws.StringValue(actual.HttpTokens) {
+		return true
+	}
+	return false
+}
+
+// isInstanceProfileDrifted checks that the instance is still using the instance profile
+// named on the AWSNodeTemplate. It intentionally does not compare the profile role's IAM
+// trust policy against any "expected" document: nothing in AWSNodeTemplate (or elsewhere
+// in this tree) captures what that trust policy should be, so there is no source of truth
+// to diff against, and wiring a hardcoded expectation in here would misreport drift on
+// every real-world role that has more than a single minimal trust statement.
+func (c *CloudProvider) isInstanceProfileDrifted(_ context.Context, instance *ec2.Instance, nodeTemplate *v1alpha1.AWSNodeTemplate) (bool, error) {
+	if nodeTemplate.Spec.InstanceProfile == nil || instance.IamInstanceProfile == nil {
+		return false, nil
+	}
+	profileName := instanceProfileNameFromARN(aws.StringValue(instance.IamInstanceProfile.Arn))
+	return profileName != aws.StringValue(nodeTemplate.Spec.InstanceProfile), nil
+}
+
+func instanceProfileNameFromARN(arnStr string) string {
+	parts := strings.SplitN(arnStr, "/", 2)
+	if len(parts) != 2 {
+		return arnStr
+	}
+	return parts[1]
+}
+
+// isUserDataDrifted compares a hash of the inputs that determine this Machine's user-data
+// against the hash recorded on the Machine when it was launched. Re-rendering the full
+// launch template just to diff user-data is expensive and the launch template provider
+// doesn't expose the rendered template after the fact, so Create stamps the hash up front
+// and drift detection only needs to recompute and compare it.
+func isUserDataDrifted(machine *v1alpha5.Machine, provisioner *v1alpha5.Provisioner, nodeTemplate *v1alpha1.AWSNodeTemplate) bool {
+	recorded, ok := machine.Annotations[UserDataHashAnnotationKey]
+	if !ok {
+		return false
+	}
+	return recorded != userDataHash(provisioner, nodeTemplate)
+}
+
+func userDataHash(provisioner *v1alpha5.Provisioner, nodeTemplate *v1alpha1.AWSNodeTemplate) string {
+	h := sha256.New()
+	_, _ = h.Write([]byte(aws.StringValue(nodeTemplate.Spec.UserData)))
+	tags, _ := json.Marshal(nodeTemplate.Spec.Tags)
+	_, _ = h.Write(tags)
+	if provisioner != nil && provisioner.Spec.KubeletConfiguration != nil {
+		kc, _ := json.Marshal(provisioner.Spec.KubeletConfiguration)
+		_, _ = h.Write(kc)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func setsEqual(a, b []string) bool {
+	a, b = lo.Uniq(a), lo.Uniq(b)
+	if len(a) != len(b) {
+		return false
+	}
+	return len(lo.Without(a, b...)) == 0 && len(lo.Without(b, a...)) == 0
+}
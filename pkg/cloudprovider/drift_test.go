@@ -0,0 +1,78 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	"github.com/aws/karpenter/pkg/apis/v1alpha1"
+)
+
+func TestIsSecurityGroupDrifted(t *testing.T) {
+	nodeTemplate := &v1alpha1.AWSNodeTemplate{}
+	nodeTemplate.Status.SecurityGroups = []v1alpha1.SecurityGroup{{ID: "sg-1"}, {ID: "sg-2"}}
+
+	instance := &ec2.Instance{
+		NetworkInterfaces: []*ec2.InstanceNetworkInterface{
+			{Groups: []*ec2.GroupIdentifier{{GroupId: aws.String("sg-1")}, {GroupId: aws.String("sg-2")}}},
+		},
+	}
+	if isSecurityGroupDrifted(instance, nodeTemplate) {
+		t.Error("expected no drift when security groups match")
+	}
+
+	drifted := &ec2.Instance{
+		NetworkInterfaces: []*ec2.InstanceNetworkInterface{
+			{Groups: []*ec2.GroupIdentifier{{GroupId: aws.String("sg-1")}, {GroupId: aws.String("sg-3")}}},
+		},
+	}
+	if !isSecurityGroupDrifted(drifted, nodeTemplate) {
+		t.Error("expected drift when instance has a security group not in the AWSNodeTemplate")
+	}
+
+	if isSecurityGroupDrifted(drifted, &v1alpha1.AWSNodeTemplate{}) {
+		t.Error("expected no drift when AWSNodeTemplate has no resolved security groups yet")
+	}
+}
+
+func TestIsSubnetDrifted(t *testing.T) {
+	nodeTemplate := &v1alpha1.AWSNodeTemplate{}
+	nodeTemplate.Status.Subnets = []v1alpha1.Subnet{{ID: "subnet-1"}, {ID: "subnet-2"}}
+
+	if isSubnetDrifted(&ec2.Instance{SubnetId: aws.String("subnet-2")}, nodeTemplate) {
+		t.Error("expected no drift when instance subnet is in the resolved set")
+	}
+	if !isSubnetDrifted(&ec2.Instance{SubnetId: aws.String("subnet-3")}, nodeTemplate) {
+		t.Error("expected drift when instance subnet is not in the resolved set")
+	}
+}
+
+func TestIsMetadataOptionsDrifted(t *testing.T) {
+	nodeTemplate := &v1alpha1.AWSNodeTemplate{}
+	nodeTemplate.Spec.MetadataOptions = &v1alpha1.MetadataOptions{HTTPTokens: aws.String("required")}
+
+	if isMetadataOptionsDrifted(&ec2.Instance{MetadataOptions: &ec2.InstanceMetadataOptionsResponse{HttpTokens: aws.String("required")}}, nodeTemplate) {
+		t.Error("expected no drift when HTTPTokens matches")
+	}
+	if !isMetadataOptionsDrifted(&ec2.Instance{MetadataOptions: &ec2.InstanceMetadataOptionsResponse{HttpTokens: aws.String("optional")}}, nodeTemplate) {
+		t.Error("expected drift when HTTPTokens differs")
+	}
+	if isMetadataOptionsDrifted(&ec2.Instance{}, nodeTemplate) {
+		t.Error("expected no drift when instance has no MetadataOptions to compare")
+	}
+}
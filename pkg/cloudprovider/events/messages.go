@@ -0,0 +1,125 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DetailType identifies one of the EventBridge message schemas this package understands.
+// These are the exact "detail-type" values EC2 and AWS Health put on the events Karpenter
+// cares about.
+type DetailType string
+
+const (
+	SpotInterruptionDetailType DetailType = "EC2 Spot Instance Interruption Warning"
+	RebalanceDetailType        DetailType = "EC2 Instance Rebalance Recommendation"
+	StateChangeDetailType      DetailType = "EC2 Instance State-change Notification"
+	HealthEventDetailType      DetailType = "AWS Health Event"
+)
+
+// envelope is the common EventBridge event wrapper every message schema below is nested in.
+type envelope struct {
+	Source     string          `json:"source"`
+	DetailType DetailType      `json:"detail-type"`
+	Detail     json.RawMessage `json:"detail"`
+}
+
+// InterruptionMessage is the parsed, schema-specific content of one EventBridge event,
+// normalized down to what the interruption reconciler actually needs: which instance,
+// why, and by when.
+type InterruptionMessage struct {
+	Kind       DetailType
+	InstanceID string
+	// Deadline is the point by which the instance will be gone. For ITNs this is derived
+	// from the two-minute warning window AWS documents; rebalance recommendations have no
+	// documented window and use rebalanceWarning; for state-change and health events it is
+	// effectively "now".
+	Deadline time.Time
+}
+
+type spotInterruptionDetail struct {
+	InstanceID     string `json:"instance-id"`
+	InstanceAction string `json:"instance-action"`
+}
+
+type rebalanceDetail struct {
+	InstanceID string `json:"instance-id"`
+}
+
+type stateChangeDetail struct {
+	InstanceID string `json:"instance-id"`
+	State      string `json:"state"`
+}
+
+type healthEventDetail struct {
+	AffectedEntities []struct {
+		EntityValue string `json:"entityValue"`
+	} `json:"affectedEntities"`
+}
+
+// spotInterruptionWarning is the EC2 documented notice period between an ITN and reclaim.
+const spotInterruptionWarning = 2 * time.Minute
+
+// rebalanceWarning is treated conservatively: AWS gives no fixed window for a rebalance
+// recommendation, so Karpenter schedules a replacement immediately but doesn't assume the
+// instance is already gone.
+const rebalanceWarning = 2 * time.Minute
+
+// Parse decodes a raw SQS message body into an InterruptionMessage. It returns
+// (nil, nil) for event types this package doesn't act on, so callers can delete the
+// message without treating it as an error.
+func Parse(body []byte, now time.Time) (*InterruptionMessage, error) {
+	var e envelope
+	if err := json.Unmarshal(body, &e); err != nil {
+		return nil, fmt.Errorf("unmarshalling event envelope, %w", err)
+	}
+	switch e.DetailType {
+	case SpotInterruptionDetailType:
+		var d spotInterruptionDetail
+		if err := json.Unmarshal(e.Detail, &d); err != nil {
+			return nil, fmt.Errorf("unmarshalling spot interruption detail, %w", err)
+		}
+		return &InterruptionMessage{Kind: e.DetailType, InstanceID: d.InstanceID, Deadline: now.Add(spotInterruptionWarning)}, nil
+	case RebalanceDetailType:
+		var d rebalanceDetail
+		if err := json.Unmarshal(e.Detail, &d); err != nil {
+			return nil, fmt.Errorf("unmarshalling rebalance detail, %w", err)
+		}
+		return &InterruptionMessage{Kind: e.DetailType, InstanceID: d.InstanceID, Deadline: now.Add(rebalanceWarning)}, nil
+	case StateChangeDetailType:
+		var d stateChangeDetail
+		if err := json.Unmarshal(e.Detail, &d); err != nil {
+			return nil, fmt.Errorf("unmarshalling state-change detail, %w", err)
+		}
+		if d.State != "stopping" && d.State != "stopped" && d.State != "shutting-down" && d.State != "terminated" {
+			return nil, nil
+		}
+		return &InterruptionMessage{Kind: e.DetailType, InstanceID: d.InstanceID, Deadline: now}, nil
+	case HealthEventDetailType:
+		var d healthEventDetail
+		if err := json.Unmarshal(e.Detail, &d); err != nil {
+			return nil, fmt.Errorf("unmarshalling health event detail, %w", err)
+		}
+		if len(d.AffectedEntities) == 0 {
+			return nil, nil
+		}
+		return &InterruptionMessage{Kind: e.DetailType, InstanceID: d.AffectedEntities[0].EntityValue, Deadline: now}, nil
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,128 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("spot interruption", func(t *testing.T) {
+		body := `{"detail-type":"EC2 Spot Instance Interruption Warning","detail":{"instance-id":"i-1","instance-action":"terminate"}}`
+		msg, err := Parse([]byte(body), now)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if msg == nil || msg.InstanceID != "i-1" || msg.Kind != SpotInterruptionDetailType {
+			t.Fatalf("got %+v", msg)
+		}
+		if !msg.Deadline.Equal(now.Add(spotInterruptionWarning)) {
+			t.Errorf("deadline = %v, want %v", msg.Deadline, now.Add(spotInterruptionWarning))
+		}
+	})
+
+	t.Run("rebalance recommendation", func(t *testing.T) {
+		body := `{"detail-type":"EC2 Instance Rebalance Recommendation","detail":{"instance-id":"i-2"}}`
+		msg, err := Parse([]byte(body), now)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if msg == nil || msg.InstanceID != "i-2" || msg.Kind != RebalanceDetailType {
+			t.Fatalf("got %+v", msg)
+		}
+		if !msg.Deadline.Equal(now.Add(rebalanceWarning)) {
+			t.Errorf("deadline = %v, want %v", msg.Deadline, now.Add(rebalanceWarning))
+		}
+	})
+
+	t.Run("state-change to a stopping or terminal state is returned", func(t *testing.T) {
+		for _, state := range []string{"stopping", "stopped", "shutting-down", "terminated"} {
+			body := `{"detail-type":"EC2 Instance State-change Notification","detail":{"instance-id":"i-3","state":"` + state + `"}}`
+			msg, err := Parse([]byte(body), now)
+			if err != nil {
+				t.Fatalf("state %q: unexpected error: %v", state, err)
+			}
+			if msg == nil || msg.InstanceID != "i-3" || msg.Kind != StateChangeDetailType {
+				t.Fatalf("state %q: got %+v", state, msg)
+			}
+			if !msg.Deadline.Equal(now) {
+				t.Errorf("state %q: deadline = %v, want %v", state, msg.Deadline, now)
+			}
+		}
+	})
+
+	t.Run("state-change to a non-terminal state is ignored", func(t *testing.T) {
+		body := `{"detail-type":"EC2 Instance State-change Notification","detail":{"instance-id":"i-3","state":"running"}}`
+		msg, err := Parse([]byte(body), now)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if msg != nil {
+			t.Fatalf("expected nil message for non-terminal state, got %+v", msg)
+		}
+	})
+
+	t.Run("health event with affected entities", func(t *testing.T) {
+		body := `{"detail-type":"AWS Health Event","detail":{"affectedEntities":[{"entityValue":"i-4"}]}}`
+		msg, err := Parse([]byte(body), now)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if msg == nil || msg.InstanceID != "i-4" || msg.Kind != HealthEventDetailType {
+			t.Fatalf("got %+v", msg)
+		}
+		if !msg.Deadline.Equal(now) {
+			t.Errorf("deadline = %v, want %v", msg.Deadline, now)
+		}
+	})
+
+	t.Run("health event with no affected entities is ignored", func(t *testing.T) {
+		body := `{"detail-type":"AWS Health Event","detail":{"affectedEntities":[]}}`
+		msg, err := Parse([]byte(body), now)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if msg != nil {
+			t.Fatalf("expected nil message, got %+v", msg)
+		}
+	})
+
+	t.Run("unknown detail-type is ignored, not an error", func(t *testing.T) {
+		body := `{"detail-type":"Some Other Event","detail":{}}`
+		msg, err := Parse([]byte(body), now)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if msg != nil {
+			t.Fatalf("expected nil message, got %+v", msg)
+		}
+	})
+
+	t.Run("malformed envelope is an error", func(t *testing.T) {
+		if _, err := Parse([]byte(`not json`), now); err == nil {
+			t.Fatal("expected error for malformed envelope")
+		}
+	})
+
+	t.Run("malformed detail is an error", func(t *testing.T) {
+		body := `{"detail-type":"EC2 Spot Instance Interruption Warning","detail":"not an object"}`
+		if _, err := Parse([]byte(body), now); err == nil {
+			t.Fatal("expected error for malformed detail")
+		}
+	})
+}
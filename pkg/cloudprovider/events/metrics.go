@@ -0,0 +1,51 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	prometheus "github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const metricNamespace = "karpenter"
+
+// messagesProcessedTotal counts every interruption-relevant SQS message consumed, labeled
+// by its EventBridge detail-type, so operators can tell ITNs apart from rebalance
+// recommendations and health events without scraping logs.
+var messagesProcessedTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: metricNamespace,
+		Subsystem: "interruption",
+		Name:      "messages_processed_total",
+		Help:      "Count of interruption queue messages processed, labeled by event type.",
+	},
+	[]string{"event_type"},
+)
+
+// messagesDeletedTotal counts messages successfully deleted from the queue after the
+// corresponding Machine mutation succeeded.
+var messagesDeletedTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: metricNamespace,
+		Subsystem: "interruption",
+		Name:      "messages_deleted_total",
+		Help:      "Count of interruption queue messages deleted after processing, labeled by event type.",
+	},
+	[]string{"event_type"},
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(messagesProcessedTotal, messagesDeletedTotal)
+}
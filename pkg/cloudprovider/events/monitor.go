@@ -0,0 +1,181 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"k8s.io/client-go/tools/record"
+	"knative.dev/pkg/logging"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+
+	"github.com/aws/karpenter/pkg/apis/settings"
+)
+
+// longPollSeconds is the SQS WaitTimeSeconds used for ReceiveMessage. 20 is the SQS
+// maximum and keeps us from hot-looping empty receives while still reacting to a new
+// message within a second or two of it landing.
+const longPollSeconds = 20
+
+const maxMessages = 10
+
+// unconfiguredPollInterval is how long Start sleeps between checks for
+// settings.InterruptionQueueName when the queue isn't configured. Interruption handling is
+// explicitly optional (it is only enabled through settings), so an empty queue name is
+// the common, not the exceptional, case and must not turn Start into a busy loop.
+const unconfiguredPollInterval = time.Minute
+
+// pollErrorBaseDelay and pollErrorMaxDelay bound the backoff Start applies after a failed
+// pollOnce (e.g. ReceiveMessage AccessDenied after a permissions change, or the queue
+// getting deleted out from under us). AWS doesn't hold the long-poll open for that kind of
+// error the way it does for an empty receive, so without an explicit backoff here a
+// persistent failure turns the loop into a tight retry against the API.
+const pollErrorBaseDelay = time.Second
+const pollErrorMaxDelay = time.Minute
+
+// Interrupter is the subset of cloudprovider.CloudProvider the Monitor needs. Depending
+// on the interface rather than the concrete type keeps this package testable without
+// constructing a full CloudProvider.
+type Interrupter interface {
+	MachineForInstanceID(ctx context.Context, instanceID string) (*v1alpha5.Machine, error)
+	Interrupt(ctx context.Context, recorder record.EventRecorder, machine *v1alpha5.Machine, reason string, deadline time.Time) error
+}
+
+// Monitor long-polls the interruption SQS queue named by settings.InterruptionQueueName
+// and feeds every Spot ITN, rebalance recommendation, instance state-change, and AWS
+// Health event it finds into CloudProvider.Interrupt.
+type Monitor struct {
+	sqsapi      sqsiface.SQSAPI
+	interrupter Interrupter
+	recorder    record.EventRecorder
+}
+
+func NewMonitor(sqsapi sqsiface.SQSAPI, interrupter Interrupter, recorder record.EventRecorder) *Monitor {
+	return &Monitor{sqsapi: sqsapi, interrupter: interrupter, recorder: recorder}
+}
+
+// Start polls the queue until ctx is canceled. It's meant to be run as a manager Runnable
+// via mgr.Add so it shares the manager's lifecycle. Interruption handling is optional, so
+// when settings.InterruptionQueueName is unset Start backs off on unconfiguredPollInterval
+// instead of spinning: ReceiveMessage's WaitTimeSeconds can't help here since there's no
+// queue to long-poll against in the first place.
+func (m *Monitor) Start(ctx context.Context) error {
+	pollErrorDelay := pollErrorBaseDelay
+	for {
+		queueURL, err := m.resolveQueueURL(ctx)
+		if err != nil {
+			logging.FromContext(ctx).Errorf("resolving interruption queue, %v", err)
+			queueURL = ""
+		}
+		if queueURL == "" {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(unconfiguredPollInterval):
+			}
+			continue
+		}
+		if err := m.pollOnce(ctx, queueURL); err != nil {
+			logging.FromContext(ctx).Errorf("polling interruption queue, %v", err)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(pollErrorDelay):
+			}
+			if pollErrorDelay *= 2; pollErrorDelay > pollErrorMaxDelay {
+				pollErrorDelay = pollErrorMaxDelay
+			}
+			continue
+		}
+		pollErrorDelay = pollErrorBaseDelay
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+	}
+}
+
+func (m *Monitor) resolveQueueURL(ctx context.Context) (string, error) {
+	name := settings.FromContext(ctx).InterruptionQueueName
+	if name == "" {
+		return "", nil
+	}
+	out, err := m.sqsapi.GetQueueUrlWithContext(ctx, &sqs.GetQueueUrlInput{QueueName: aws.String(name)})
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(out.QueueUrl), nil
+}
+
+func (m *Monitor) pollOnce(ctx context.Context, queueURL string) error {
+	out, err := m.sqsapi.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(queueURL),
+		MaxNumberOfMessages: aws.Int64(maxMessages),
+		WaitTimeSeconds:     aws.Int64(longPollSeconds),
+	})
+	if err != nil {
+		return err
+	}
+	for _, msg := range out.Messages {
+		m.handle(ctx, queueURL, msg)
+	}
+	return nil
+}
+
+// handle processes a single SQS message. The message is only deleted once the Machine
+// mutation it implies has succeeded; a message that can't yet be actioned is left on the
+// queue to be retried (and, after enough receives, to land in the dead-letter queue the
+// caller configured on the source queue's redrive policy).
+func (m *Monitor) handle(ctx context.Context, queueURL string, msg *sqs.Message) {
+	parsed, err := Parse([]byte(aws.StringValue(msg.Body)), time.Now())
+	if err != nil {
+		logging.FromContext(ctx).Errorf("parsing interruption message, %v", err)
+		return
+	}
+	if parsed == nil {
+		m.delete(ctx, queueURL, msg, "")
+		return
+	}
+	messagesProcessedTotal.WithLabelValues(string(parsed.Kind)).Inc()
+	machine, err := m.interrupter.MachineForInstanceID(ctx, parsed.InstanceID)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("resolving machine for instance %s, %v", parsed.InstanceID, err)
+		return
+	}
+	if err := m.interrupter.Interrupt(ctx, m.recorder, machine, string(parsed.Kind), parsed.Deadline); err != nil {
+		logging.FromContext(ctx).Errorf("interrupting machine %s, %v", machine.Name, err)
+		return
+	}
+	m.delete(ctx, queueURL, msg, string(parsed.Kind))
+}
+
+func (m *Monitor) delete(ctx context.Context, queueURL string, msg *sqs.Message, eventType string) {
+	if _, err := m.sqsapi.DeleteMessageWithContext(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(queueURL),
+		ReceiptHandle: msg.ReceiptHandle,
+	}); err != nil {
+		logging.FromContext(ctx).Errorf("deleting interruption message, %v", err)
+		return
+	}
+	if eventType != "" {
+		messagesDeletedTotal.WithLabelValues(eventType).Inc()
+	}
+}
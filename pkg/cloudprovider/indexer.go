@@ -0,0 +1,41 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+
+	"github.com/aws/karpenter/pkg/utils"
+)
+
+// RegisterIndexers registers the field indexes MachineForInstanceID depends on against
+// mgr's cache. It must be called once while building the manager, before any controller
+// that calls MachineForInstanceID starts running - a List using an unregistered field index
+// errors on every call.
+func RegisterIndexers(ctx context.Context, mgr manager.Manager) error {
+	return mgr.GetFieldIndexer().IndexField(ctx, &v1alpha5.Machine{}, MachineIndexFieldInstanceID, func(obj client.Object) []string {
+		machine := obj.(*v1alpha5.Machine)
+		id, err := utils.ParseInstanceID(machine.Status.ProviderID)
+		if err != nil {
+			return nil
+		}
+		return []string{id}
+	})
+}
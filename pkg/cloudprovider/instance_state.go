@@ -0,0 +1,104 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/patrickmn/go-cache"
+	"k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/aws/karpenter/pkg/utils"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+)
+
+const (
+	// instanceStateCacheTTL must stay longer than instancestate.Controller's pollInterval,
+	// or every poll would land after the cache entry populated by the previous List has
+	// already expired, forcing a per-Machine DescribeInstances call on effectively every
+	// reconcile instead of hitting the batched List cache.
+	instanceStateCacheTTL             = 5 * time.Minute
+	instanceStateCacheCleanupInterval = 10 * time.Minute
+)
+
+// InstanceState is the lifecycle state of the EC2 instance backing a Machine, collapsed
+// from ec2.Instance.State.Name into the states a controller actually needs to act on.
+type InstanceState string
+
+const (
+	InstanceStateRunning    InstanceState = "Running"
+	InstanceStateStopping   InstanceState = "Stopping"
+	InstanceStateStopped    InstanceState = "Stopped"
+	InstanceStateTerminated InstanceState = "Terminated"
+	InstanceStateUnknown    InstanceState = "Unknown"
+)
+
+func instanceStateFromEC2(name *string) InstanceState {
+	switch aws.StringValue(name) {
+	case ec2.InstanceStateNamePending, ec2.InstanceStateNameRunning:
+		return InstanceStateRunning
+	case ec2.InstanceStateNameStopping:
+		return InstanceStateStopping
+	case ec2.InstanceStateNameStopped:
+		return InstanceStateStopped
+	case ec2.InstanceStateNameShuttingDown, ec2.InstanceStateNameTerminated:
+		return InstanceStateTerminated
+	default:
+		return InstanceStateUnknown
+	}
+}
+
+// InstanceState returns the current lifecycle state of the EC2 instance backing machine.
+// It consults the instance cache populated by List before falling back to a single
+// DescribeInstances call, so a reconcile loop iterating every Machine doesn't issue one
+// DescribeInstances call per Machine. An InvalidInstanceID.NotFound error (the instance
+// disappeared between List and now) is mapped to InstanceStateTerminated rather than
+// returned as an error, since that race is expected, not exceptional.
+func (c *CloudProvider) InstanceState(ctx context.Context, machine *v1alpha5.Machine) (InstanceState, error) {
+	id, err := utils.ParseInstanceID(machine.Status.ProviderID)
+	if err != nil {
+		return InstanceStateUnknown, fmt.Errorf("getting instance ID, %w", err)
+	}
+	if cached, ok := c.instanceCache.Get(id); ok {
+		state := instanceStateFromEC2(cached.(*ec2.Instance).State.Name)
+		if state == InstanceStateTerminated {
+			c.invalidateProvisionerCache(id)
+		}
+		return state, nil
+	}
+	instance, err := c.instanceProvider.Get(ctx, id)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.invalidateProvisionerCache(id)
+			return InstanceStateTerminated, nil
+		}
+		return InstanceStateUnknown, fmt.Errorf("getting instance, %w", err)
+	}
+	c.instanceCache.SetDefault(id, instance)
+	state := instanceStateFromEC2(instance.State.Name)
+	if state == InstanceStateTerminated {
+		c.invalidateProvisionerCache(id)
+	}
+	return state, nil
+}
+
+func newInstanceCache() *cache.Cache {
+	return cache.New(instanceStateCacheTTL, instanceStateCacheCleanupInterval)
+}
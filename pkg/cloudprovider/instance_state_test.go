@@ -0,0 +1,46 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func TestInstanceStateFromEC2(t *testing.T) {
+	cases := []struct {
+		name  string
+		state *string
+		want  InstanceState
+	}{
+		{name: "pending", state: aws.String(ec2.InstanceStateNamePending), want: InstanceStateRunning},
+		{name: "running", state: aws.String(ec2.InstanceStateNameRunning), want: InstanceStateRunning},
+		{name: "stopping", state: aws.String(ec2.InstanceStateNameStopping), want: InstanceStateStopping},
+		{name: "stopped", state: aws.String(ec2.InstanceStateNameStopped), want: InstanceStateStopped},
+		{name: "shutting-down", state: aws.String(ec2.InstanceStateNameShuttingDown), want: InstanceStateTerminated},
+		{name: "terminated", state: aws.String(ec2.InstanceStateNameTerminated), want: InstanceStateTerminated},
+		{name: "nil", state: nil, want: InstanceStateUnknown},
+		{name: "unrecognized", state: aws.String("bogus"), want: InstanceStateUnknown},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := instanceStateFromEC2(tc.state); got != tc.want {
+				t.Errorf("instanceStateFromEC2(%v) = %v, want %v", tc.state, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+)
+
+// InterruptedAnnotationKey records why and by when a Machine is being proactively
+// replaced because of an upstream interruption signal (Spot ITN, rebalance
+// recommendation, state-change notification, or health event), so the disruption
+// controller can distinguish this from a drift- or expiration-driven replacement.
+const InterruptedAnnotationKey = "karpenter.k8s.aws/interrupted"
+
+// MachineIndexFieldInstanceID is the field index name the controller-runtime manager must
+// register against v1alpha5.Machine so MachineForInstanceID can look machines up by bare
+// EC2 instance id in O(1) instead of listing every Machine on every interruption message.
+// The index value is the instance id parsed out of Status.ProviderID (utils.ParseInstanceID),
+// not the provider ID itself: the SQS message schemas the events package parses (messages.go) only
+// ever carry the bare instance id, never the "aws:///<az>/<instance-id>" provider ID format.
+const MachineIndexFieldInstanceID = "status.providerID.instanceID"
+
+// MachineForInstanceID returns the live Machine backed by the EC2 instance with the given
+// bare instance id (e.g. "i-0123456789abcdef0"). It depends on the manager having indexed
+// Machines by MachineIndexFieldInstanceID.
+func (c *CloudProvider) MachineForInstanceID(ctx context.Context, instanceID string) (*v1alpha5.Machine, error) {
+	machines := &v1alpha5.MachineList{}
+	if err := c.kubeClient.List(ctx, machines, client.MatchingFields{MachineIndexFieldInstanceID: instanceID}); err != nil {
+		return nil, fmt.Errorf("listing machines, %w", err)
+	}
+	if len(machines.Items) == 0 {
+		return nil, errors.NewNotFound(schema.GroupResource{Group: v1alpha5.Group, Resource: "Machine"}, instanceID)
+	}
+	return &machines.Items[0], nil
+}
+
+// Interrupt proactively replaces a Machine whose backing instance is about to disappear
+// because of an out-of-band EC2 event. It annotates the Machine with
+// InterruptedAnnotationKey, cordons the Node with the same provider ID so the scheduler
+// stops placing new pods on it, emits a Kubernetes Event recording why, and launches a
+// replacement along the normal Create path before deadline so workloads have somewhere
+// to move to ahead of the interruption rather than after it.
+func (c *CloudProvider) Interrupt(ctx context.Context, recorder record.EventRecorder, machine *v1alpha5.Machine, reason string, deadline time.Time) error {
+	if machine.Annotations == nil {
+		machine.Annotations = map[string]string{}
+	}
+	machine.Annotations[InterruptedAnnotationKey] = fmt.Sprintf("%s (deadline %s)", reason, deadline.UTC().Format(time.RFC3339))
+	if err := c.kubeClient.Update(ctx, machine); err != nil {
+		return fmt.Errorf("annotating machine, %w", err)
+	}
+	nodes := &v1.NodeList{}
+	if err := c.kubeClient.List(ctx, nodes); err != nil {
+		return fmt.Errorf("listing nodes, %w", err)
+	}
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		if node.Spec.ProviderID != machine.Status.ProviderID || node.Spec.Unschedulable {
+			continue
+		}
+		stored := node.DeepCopy()
+		node.Spec.Unschedulable = true
+		if err := c.kubeClient.Patch(ctx, node, client.MergeFrom(stored)); err != nil {
+			return fmt.Errorf("cordoning node, %w", err)
+		}
+	}
+	if recorder != nil {
+		recorder.Eventf(machine, v1.EventTypeWarning, "InstanceInterrupted", "Instance interrupted (%s), launching replacement before %s", reason, deadline.UTC().Format(time.RFC3339))
+	}
+	replacement := machine.DeepCopy()
+	replacement.ObjectMeta = metav1.ObjectMeta{
+		GenerateName: machine.Name + "-",
+		Labels:       machine.Labels,
+		Annotations:  map[string]string{v1alpha5.ProviderCompatabilityAnnotationKey: machine.Annotations[v1alpha5.ProviderCompatabilityAnnotationKey]},
+	}
+	// The DeepCopy above carries over machine's Status (ProviderID, Capacity, conditions, ...)
+	// from the instance being replaced. Create only reads Spec/Labels/Annotations, but a
+	// stale Status here would otherwise get persisted below against a Machine that doesn't
+	// have an instance behind it yet.
+	replacement.Status = v1alpha5.MachineStatus{}
+	created, err := c.Create(ctx, replacement)
+	if err != nil {
+		return fmt.Errorf("launching replacement instance, %w", err)
+	}
+	// Create only calls RunInstances and builds the Machine struct in memory; nothing on
+	// that path ever persists it, so without this the replacement instance is launched and
+	// billed but never tracked as a Machine object.
+	if err := c.kubeClient.Create(ctx, created); err != nil {
+		return fmt.Errorf("registering replacement machine, %w", err)
+	}
+	return nil
+}
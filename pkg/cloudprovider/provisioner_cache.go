@@ -0,0 +1,155 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/patrickmn/go-cache"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/logging"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+)
+
+// provisionerCacheTTL is chosen to comfortably outlive the multi-second window between
+// RunInstances returning and the karpenter.sh/provisioner-name tag becoming visible on
+// DescribeInstances, so List and Get never have to fall back to a tag scan for an
+// instance Create just launched.
+const provisionerCacheTTL = 15 * time.Minute
+const provisionerCacheCleanupInterval = 30 * time.Minute
+
+// describeTagsRetries and describeTagsBaseDelay bound the backoff used when a tag scan
+// comes back empty: rather than treating a missing tag as authoritative (it may simply
+// not have propagated yet), we retry a direct DescribeTags call a few times before giving
+// up and returning NotFound.
+const describeTagsRetries = 4
+const describeTagsBaseDelay = 250 * time.Millisecond
+
+// tagPropagationRacesTotal counts every time resolveProvisionerFromInstance had to fall
+// back to DescribeTags (once per resolution, however many attempts that takes) because
+// the provisioner-name tag wasn't yet present on the DescribeInstances response, so
+// operators can tell whether
+// provisionerCacheTTL needs to be longer.
+var tagPropagationRacesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "karpenter",
+	Subsystem: "cloudprovider",
+	Name:      "tag_propagation_races_total",
+	Help:      "Count of instance-to-provisioner resolutions that had to fall back to DescribeTags because the provisioner-name tag had not yet propagated to DescribeInstances.",
+})
+
+func init() {
+	crmetrics.Registry.MustRegister(tagPropagationRacesTotal)
+}
+
+func newProvisionerCache() *cache.Cache {
+	return cache.New(provisionerCacheTTL, provisionerCacheCleanupInterval)
+}
+
+// cacheProvisionerForInstance records the provisioner a Create call launched instanceID
+// under. Create is the one place we know the provisioner with certainty, so populating
+// the cache there means List and Get never need to trust a tag that may not have
+// propagated yet.
+func (c *CloudProvider) cacheProvisionerForInstance(instanceID, provisionerName string) {
+	c.provisionerCache.SetDefault(instanceID, provisionerName)
+}
+
+// invalidateProvisionerCache drops instanceID from the cache. Called on Delete and when
+// an instance is observed Terminated, since a stale entry for a gone instance is pure
+// downside (the instance-id could theoretically be reused by a future, unrelated Create,
+// though EC2 makes this practically impossible within the TTL).
+func (c *CloudProvider) invalidateProvisionerCache(instanceID string) {
+	c.provisionerCache.Delete(instanceID)
+}
+
+// resolveProvisionerFromInstance resolves the Provisioner that owns instance. It replaces
+// the old pure tag-scan implementation: the karpenter.sh/provisioner-name tag can lag
+// DescribeInstances by several seconds after RunInstances returns, so trusting the tag
+// alone causes List to silently drop just-launched instances. See provisionerNameForInstance.
+func (c *CloudProvider) resolveProvisionerFromInstance(ctx context.Context, instance *ec2.Instance) (*v1alpha5.Provisioner, error) {
+	provisionerName, ok := c.provisionerNameForInstance(ctx, instance)
+	if !ok {
+		return nil, errors.NewNotFound(schema.GroupResource{Group: v1alpha5.Group, Resource: "Provisioner"}, "")
+	}
+	provisioner := &v1alpha5.Provisioner{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: provisionerName}, provisioner); err != nil {
+		return nil, err
+	}
+	return provisioner, nil
+}
+
+// provisionerNameForInstance resolves the provisioner name for instance, preferring the
+// cache populated at Create time, then the provisioner-name tag on the DescribeInstances
+// response, then (if the tag hasn't propagated yet) a direct, retried DescribeTags call.
+func (c *CloudProvider) provisionerNameForInstance(ctx context.Context, instance *ec2.Instance) (string, bool) {
+	instanceID := aws.StringValue(instance.InstanceId)
+	if cached, ok := c.provisionerCache.Get(instanceID); ok {
+		return cached.(string), true
+	}
+	if tag, ok := lo.Find(instance.Tags, func(t *ec2.Tag) bool {
+		return aws.StringValue(t.Key) == v1alpha5.ProvisionerNameLabelKey
+	}); ok {
+		name := aws.StringValue(tag.Value)
+		c.cacheProvisionerForInstance(instanceID, name)
+		return name, true
+	}
+	tagPropagationRacesTotal.Inc()
+	name, ok := c.describeProvisionerTagWithBackoff(ctx, instanceID)
+	if ok {
+		c.cacheProvisionerForInstance(instanceID, name)
+	}
+	return name, ok
+}
+
+// describeProvisionerTagWithBackoff retries a direct DescribeTags call, backing off between
+// attempts, to cover the window before the provisioner-name tag has propagated. A non-nil
+// err from DescribeTagsWithContext (throttling, a permissions problem, ...) is logged and
+// distinguished from a merely-not-propagated-yet empty result, since the two call for very
+// different operator action. It never sleeps after the final attempt: that delay would only
+// push out the eventual NotFound, not produce a different answer.
+func (c *CloudProvider) describeProvisionerTagWithBackoff(ctx context.Context, instanceID string) (string, bool) {
+	delay := describeTagsBaseDelay
+	for attempt := 0; attempt < describeTagsRetries; attempt++ {
+		out, err := c.ec2api.DescribeTagsWithContext(ctx, &ec2.DescribeTagsInput{
+			Filters: []*ec2.Filter{
+				{Name: aws.String("resource-id"), Values: []*string{aws.String(instanceID)}},
+				{Name: aws.String("key"), Values: []*string{aws.String(v1alpha5.ProvisionerNameLabelKey)}},
+			},
+		})
+		if err != nil {
+			logging.FromContext(ctx).Errorf("describing provisioner-name tag for instance %s, %v", instanceID, err)
+		} else if len(out.Tags) > 0 {
+			return aws.StringValue(out.Tags[0].Value), true
+		}
+		if attempt == describeTagsRetries-1 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return "", false
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+	return "", false
+}
@@ -0,0 +1,113 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+)
+
+// fakeDescribeTagsEC2API stubs only DescribeTagsWithContext, returning the next entry in
+// responses on each call. Embedding ec2iface.EC2API lets it satisfy the full interface
+// without implementing every other method.
+type fakeDescribeTagsEC2API struct {
+	ec2iface.EC2API
+	responses []func() (*ec2.DescribeTagsOutput, error)
+	calls     int
+}
+
+func (f *fakeDescribeTagsEC2API) DescribeTagsWithContext(_ context.Context, _ *ec2.DescribeTagsInput, _ ...request.Option) (*ec2.DescribeTagsOutput, error) {
+	resp := f.responses[f.calls]
+	f.calls++
+	return resp()
+}
+
+func emptyTags() (*ec2.DescribeTagsOutput, error) {
+	return &ec2.DescribeTagsOutput{}, nil
+}
+
+func apiError() (*ec2.DescribeTagsOutput, error) {
+	return nil, errors.New("throttled")
+}
+
+func tagFound(name string) func() (*ec2.DescribeTagsOutput, error) {
+	return func() (*ec2.DescribeTagsOutput, error) {
+		return &ec2.DescribeTagsOutput{Tags: []*ec2.TagDescription{{Value: aws.String(name)}}}, nil
+	}
+}
+
+func TestDescribeProvisionerTagWithBackoff(t *testing.T) {
+	t.Run("found on first attempt", func(t *testing.T) {
+		fake := &fakeDescribeTagsEC2API{responses: []func() (*ec2.DescribeTagsOutput, error){tagFound("default")}}
+		c := &CloudProvider{ec2api: fake}
+		name, ok := c.describeProvisionerTagWithBackoff(context.Background(), "i-1")
+		if !ok || name != "default" {
+			t.Fatalf("got (%q, %v), want (\"default\", true)", name, ok)
+		}
+		if fake.calls != 1 {
+			t.Errorf("expected 1 call, got %d", fake.calls)
+		}
+	})
+
+	t.Run("found after propagation race", func(t *testing.T) {
+		fake := &fakeDescribeTagsEC2API{responses: []func() (*ec2.DescribeTagsOutput, error){emptyTags, emptyTags, tagFound("default")}}
+		c := &CloudProvider{ec2api: fake}
+		name, ok := c.describeProvisionerTagWithBackoff(context.Background(), "i-1")
+		if !ok || name != "default" {
+			t.Fatalf("got (%q, %v), want (\"default\", true)", name, ok)
+		}
+	})
+
+	t.Run("never propagates, no trailing sleep after final attempt", func(t *testing.T) {
+		fake := &fakeDescribeTagsEC2API{responses: []func() (*ec2.DescribeTagsOutput, error){emptyTags, emptyTags, emptyTags, emptyTags}}
+		c := &CloudProvider{ec2api: fake}
+		_, ok := c.describeProvisionerTagWithBackoff(context.Background(), "i-1")
+		if ok {
+			t.Fatal("expected not-found")
+		}
+		if fake.calls != describeTagsRetries {
+			t.Errorf("expected exactly %d calls, got %d", describeTagsRetries, fake.calls)
+		}
+	})
+
+	t.Run("persistent API error is retried, not conflated with success", func(t *testing.T) {
+		fake := &fakeDescribeTagsEC2API{responses: []func() (*ec2.DescribeTagsOutput, error){apiError, apiError, apiError, apiError}}
+		c := &CloudProvider{ec2api: fake}
+		_, ok := c.describeProvisionerTagWithBackoff(context.Background(), "i-1")
+		if ok {
+			t.Fatal("expected not-found on persistent API error")
+		}
+		if fake.calls != describeTagsRetries {
+			t.Errorf("expected exactly %d calls, got %d", describeTagsRetries, fake.calls)
+		}
+	})
+
+	t.Run("context cancellation stops retries", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		fake := &fakeDescribeTagsEC2API{responses: []func() (*ec2.DescribeTagsOutput, error){emptyTags, emptyTags, emptyTags, emptyTags}}
+		c := &CloudProvider{ec2api: fake}
+		_, ok := c.describeProvisionerTagWithBackoff(ctx, "i-1")
+		if ok {
+			t.Fatal("expected not-found when context is already canceled")
+		}
+	})
+}
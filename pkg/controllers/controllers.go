@@ -0,0 +1,45 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package controllers wires every controller and background Runnable this repo owns into
+// a single entrypoint, so cmd/controller only needs one call to build a complete manager.
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/aws/karpenter/pkg/cloudprovider"
+	"github.com/aws/karpenter/pkg/controllers/instancestate"
+)
+
+// Register builds every controller in this module and adds them (along with every
+// background Runnable, such as CloudProvider's interruption events.Monitor) to mgr. It also
+// registers the field indexes those controllers depend on, since a Machine List using an
+// unregistered index fails on every call rather than at startup.
+func Register(ctx context.Context, mgr manager.Manager, cloudProvider *cloudprovider.CloudProvider) error {
+	if err := cloudprovider.RegisterIndexers(ctx, mgr); err != nil {
+		return fmt.Errorf("registering field indexers, %w", err)
+	}
+	instanceStateController := instancestate.NewController(mgr.GetClient(), cloudProvider)
+	if err := instanceStateController.Builder(mgr).Complete(instanceStateController); err != nil {
+		return fmt.Errorf("registering instancestate controller, %w", err)
+	}
+	if err := mgr.Add(cloudProvider.EventsMonitor()); err != nil {
+		return fmt.Errorf("registering interruption events monitor, %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package instancestate reconciles Machines against the lifecycle state of the EC2
+// instance backing them, so that an instance stopped or shut down out-of-band (an
+// Auto-Scaling detach, a Spot rebalance, a manual stop) doesn't sit around looking
+// healthy until the node's heartbeat eventually times out.
+package instancestate
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+
+	"github.com/aws/karpenter/pkg/cloudprovider"
+)
+
+// pollInterval is how often a Machine's instance lifecycle state is rechecked, since
+// Machines aren't otherwise re-reconciled in response to out-of-band EC2 state changes.
+// InstanceState answers from the instance cache while an entry is present, so the state a
+// poll sees can be as old as cloudprovider.instanceStateCacheTTL. It must stay below that
+// TTL so InstanceState keeps hitting the batched List cache instead of issuing a
+// DescribeInstances call per Machine per poll.
+const pollInterval = time.Minute
+
+// stoppingPollInterval is used instead of pollInterval once an instance is seen Stopping:
+// that's not yet terminal (e.g. a managed stop/start reboot cycle can resume on its own),
+// so we poll more often, without tearing the Machine down while its outcome is still
+// ambiguous. A poll only sees the resolution (Stopped or back to Running) once the cached
+// instance entry has been refreshed or has expired (see pollInterval).
+const stoppingPollInterval = 15 * time.Second
+
+// NotReadyAnnotationKey marks a Machine whose instance is Stopping as not ready to receive
+// work, without the destructive, harder-to-reverse step of deleting it outright. Scheduling
+// controllers can treat its presence the same as a NotReady node condition.
+const NotReadyAnnotationKey = "karpenter.k8s.aws/not-ready"
+
+// InstanceStateGetter is the subset of cloudprovider.CloudProvider the Controller needs.
+// Depending on the interface rather than the concrete type keeps this package testable
+// without constructing a full CloudProvider.
+type InstanceStateGetter interface {
+	InstanceState(ctx context.Context, machine *v1alpha5.Machine) (cloudprovider.InstanceState, error)
+}
+
+// Controller reaps Machines whose backing EC2 instance has reached a terminal lifecycle
+// state (Stopped or Terminated) so the scheduler treats the capacity as gone and
+// replacement nodes come up immediately rather than waiting for the node's heartbeat to
+// expire. A merely Stopping instance is marked NotReady instead of deleted, since stopping
+// is not yet a terminal state.
+type Controller struct {
+	kubeClient    client.Client
+	cloudProvider InstanceStateGetter
+}
+
+func NewController(kubeClient client.Client, cloudProvider InstanceStateGetter) *Controller {
+	return &Controller{kubeClient: kubeClient, cloudProvider: cloudProvider}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	machine := &v1alpha5.Machine{}
+	if err := c.kubeClient.Get(ctx, req.NamespacedName, machine); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	if machine.Status.ProviderID == "" {
+		return reconcile.Result{}, nil
+	}
+	state, err := c.cloudProvider.InstanceState(ctx, machine)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	switch state {
+	case cloudprovider.InstanceStateStopped, cloudprovider.InstanceStateTerminated:
+		if err := c.kubeClient.Delete(ctx, machine); err != nil && !errors.IsNotFound(err) {
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{}, nil
+	case cloudprovider.InstanceStateStopping:
+		if _, ok := machine.Annotations[NotReadyAnnotationKey]; !ok {
+			if machine.Annotations == nil {
+				machine.Annotations = map[string]string{}
+			}
+			machine.Annotations[NotReadyAnnotationKey] = "true"
+			if err := c.kubeClient.Update(ctx, machine); err != nil {
+				return reconcile.Result{}, err
+			}
+		}
+		return reconcile.Result{RequeueAfter: stoppingPollInterval}, nil
+	default:
+		return reconcile.Result{RequeueAfter: pollInterval}, nil
+	}
+}
+
+func (c *Controller) Builder(mgr manager.Manager) *builder.Builder {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha5.Machine{}).
+		Named("instancestate")
+}
@@ -0,0 +1,135 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancestate
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/aws/karpenter-core/pkg/apis/v1alpha5"
+
+	"github.com/aws/karpenter/pkg/cloudprovider"
+)
+
+// fakeInstanceStateGetter reports a fixed InstanceState (or error) for every Machine,
+// regardless of which Machine is asked about, which is all these table-driven tests need.
+type fakeInstanceStateGetter struct {
+	state cloudprovider.InstanceState
+	err   error
+}
+
+func (f *fakeInstanceStateGetter) InstanceState(context.Context, *v1alpha5.Machine) (cloudprovider.InstanceState, error) {
+	return f.state, f.err
+}
+
+func newTestController(t *testing.T, machine *v1alpha5.Machine, state cloudprovider.InstanceState) (*Controller, client.Client) {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := v1alpha5.AddToScheme(scheme); err != nil {
+		t.Fatalf("adding v1alpha5 to scheme, %v", err)
+	}
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(machine).Build()
+	return NewController(kubeClient, &fakeInstanceStateGetter{state: state}), kubeClient
+}
+
+func testMachine() *v1alpha5.Machine {
+	machine := &v1alpha5.Machine{ObjectMeta: metav1.ObjectMeta{Name: "test-machine"}}
+	machine.Status.ProviderID = "aws:///us-east-1a/i-0123456789abcdef0"
+	return machine
+}
+
+func TestControllerReconcile(t *testing.T) {
+	t.Run("no providerID yet is a no-op", func(t *testing.T) {
+		machine := &v1alpha5.Machine{ObjectMeta: metav1.ObjectMeta{Name: "test-machine"}}
+		c, kubeClient := newTestController(t, machine, cloudprovider.InstanceStateRunning)
+		result, err := c.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Name: machine.Name}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.RequeueAfter != 0 {
+			t.Errorf("expected no requeue, got %v", result.RequeueAfter)
+		}
+		got := &v1alpha5.Machine{}
+		if err := kubeClient.Get(context.Background(), types.NamespacedName{Name: machine.Name}, got); err != nil {
+			t.Fatalf("machine should still exist, %v", err)
+		}
+	})
+
+	t.Run("running requeues after pollInterval and does not touch the machine", func(t *testing.T) {
+		machine := testMachine()
+		c, kubeClient := newTestController(t, machine, cloudprovider.InstanceStateRunning)
+		result, err := c.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Name: machine.Name}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.RequeueAfter != pollInterval {
+			t.Errorf("RequeueAfter = %v, want %v", result.RequeueAfter, pollInterval)
+		}
+		got := &v1alpha5.Machine{}
+		if err := kubeClient.Get(context.Background(), types.NamespacedName{Name: machine.Name}, got); err != nil {
+			t.Fatalf("machine should still exist, %v", err)
+		}
+		if _, ok := got.Annotations[NotReadyAnnotationKey]; ok {
+			t.Error("did not expect NotReadyAnnotationKey to be set")
+		}
+	})
+
+	t.Run("stopping marks NotReady and requeues sooner without deleting", func(t *testing.T) {
+		machine := testMachine()
+		c, kubeClient := newTestController(t, machine, cloudprovider.InstanceStateStopping)
+		result, err := c.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Name: machine.Name}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.RequeueAfter != stoppingPollInterval {
+			t.Errorf("RequeueAfter = %v, want %v", result.RequeueAfter, stoppingPollInterval)
+		}
+		got := &v1alpha5.Machine{}
+		if err := kubeClient.Get(context.Background(), types.NamespacedName{Name: machine.Name}, got); err != nil {
+			t.Fatalf("machine should still exist (not deleted), %v", err)
+		}
+		if got.Annotations[NotReadyAnnotationKey] != "true" {
+			t.Errorf("expected NotReadyAnnotationKey to be set, got %+v", got.Annotations)
+		}
+	})
+
+	for _, state := range []cloudprovider.InstanceState{cloudprovider.InstanceStateStopped, cloudprovider.InstanceStateTerminated} {
+		state := state
+		t.Run(string(state)+" deletes the machine", func(t *testing.T) {
+			machine := testMachine()
+			c, kubeClient := newTestController(t, machine, state)
+			result, err := c.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Name: machine.Name}})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.RequeueAfter != 0 {
+				t.Errorf("expected no requeue on delete, got %v", result.RequeueAfter)
+			}
+			got := &v1alpha5.Machine{}
+			err = kubeClient.Get(context.Background(), types.NamespacedName{Name: machine.Name}, got)
+			if !errors.IsNotFound(err) {
+				t.Fatalf("expected machine to be deleted, got err=%v", err)
+			}
+		})
+	}
+}